@@ -0,0 +1,167 @@
+package softlayer
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/softlayer/softlayer-go/datatypes"
+	"github.com/softlayer/softlayer-go/filter"
+	"github.com/softlayer/softlayer-go/services"
+	"github.com/softlayer/softlayer-go/session"
+	"github.com/softlayer/softlayer-go/sl"
+)
+
+// vlanListPageSize is the page size used when walking SoftLayer_Account::getNetworkVlans so
+// that accounts with many VLANs don't run into the API's default result limit.
+const vlanListPageSize = 100
+
+func dataSourceSoftLayerVlan() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceSoftLayerVlanRead,
+
+		Schema: map[string]*schema.Schema{
+			"rid": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"datacenter": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"vlan_number": {
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"router_hostname": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"softlayer_managed": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"child_resource_count": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"subnets": {
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"subnet": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"subnet_type": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"subnet_size": {
+							Type:     schema.TypeInt,
+							Optional: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceSoftLayerVlanRead(d *schema.ResourceData, meta interface{}) error {
+	sess := meta.(ProviderConfig).SoftLayerSession()
+
+	name := d.Get("name").(string)
+	datacenter := d.Get("datacenter").(string)
+	vlanNumber := d.Get("vlan_number").(int)
+	routerHostname := d.Get("router_hostname").(string)
+
+	if name == "" && datacenter == "" && vlanNumber == 0 && routerHostname == "" {
+		return fmt.Errorf("One of 'name', 'datacenter', 'vlan_number' or 'router_hostname' must be set")
+	}
+
+	filters := filter.New()
+	if name != "" {
+		filters = append(filters, filter.Path("networkVlans.name").Eq(name))
+	}
+	if datacenter != "" {
+		filters = append(filters, filter.Path("networkVlans.primaryRouter.datacenter.name").Eq(datacenter))
+	}
+	if vlanNumber > 0 {
+		filters = append(filters, filter.Path("networkVlans.vlanNumber").Eq(strconv.Itoa(vlanNumber)))
+	}
+	if routerHostname != "" {
+		filters = append(filters, filter.Path("networkVlans.primaryRouter.hostname").Eq(routerHostname))
+	}
+
+	vlans, err := getNetworkVlansPaged(sess, filters)
+	if err != nil {
+		return fmt.Errorf("Error retrieving vlan: %s", err)
+	}
+
+	if len(vlans) == 0 {
+		return fmt.Errorf("No vlan found matching the provided filters")
+	}
+	if len(vlans) > 1 {
+		return fmt.Errorf("More than one vlan matches the provided filters, please narrow down the search")
+	}
+
+	vlan := vlans[0]
+
+	d.SetId(fmt.Sprintf("%d", *vlan.Id))
+	d.Set("rid", *vlan.Id)
+	d.Set("vlan_number", *vlan.VlanNumber)
+	d.Set("child_resource_count", *vlan.GuestNetworkComponentCount)
+	d.Set("name", sl.Get(vlan.Name, ""))
+	d.Set("softlayer_managed", vlan.BillingItem == nil)
+
+	if vlan.PrimaryRouter != nil {
+		d.Set("router_hostname", *vlan.PrimaryRouter.Hostname)
+		if vlan.PrimaryRouter.Datacenter != nil {
+			d.Set("datacenter", *vlan.PrimaryRouter.Datacenter.Name)
+		}
+	}
+
+	subnets := make([]map[string]interface{}, 0)
+	for _, elem := range vlan.Subnets {
+		subnets = append(subnets, map[string]interface{}{
+			"subnet":      fmt.Sprintf("%s/%s", *elem.NetworkIdentifier, strconv.Itoa(*elem.Cidr)),
+			"subnet_type": *elem.SubnetType,
+			"subnet_size": 1 << uint(32-*elem.Cidr),
+		})
+	}
+	d.Set("subnets", subnets)
+
+	return nil
+}
+
+// getNetworkVlansPaged walks SoftLayer_Account::getNetworkVlans a page at a time, applying
+// filters, so that accounts with a large number of VLANs don't hit the API's default result limit.
+func getNetworkVlansPaged(sess *session.Session, filters filter.Filters) ([]datatypes.Network_Vlan, error) {
+	result := []datatypes.Network_Vlan{}
+
+	for offset := 0; ; offset += vlanListPageSize {
+		resp, err := services.GetAccountService(sess).
+			Filter(filters.Build()).
+			Mask(VlanMask).
+			Offset(offset).
+			Limit(vlanListPageSize).
+			GetNetworkVlans()
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, resp...)
+
+		if len(resp) < vlanListPageSize {
+			break
+		}
+	}
+
+	return result, nil
+}
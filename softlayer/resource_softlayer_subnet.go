@@ -0,0 +1,288 @@
+package softlayer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/softlayer/softlayer-go/datatypes"
+	"github.com/softlayer/softlayer-go/filter"
+	"github.com/softlayer/softlayer-go/helpers/product"
+	"github.com/softlayer/softlayer-go/services"
+	"github.com/softlayer/softlayer-go/session"
+	"github.com/softlayer/softlayer-go/sl"
+)
+
+const (
+	SubnetMask = "id,networkIdentifier,cidr,subnetType,gateway,totalIpAddresses," +
+		"billingItem[id],networkVlan[id]"
+)
+
+func resourceSoftLayerSubnet() *schema.Resource {
+	return &schema.Resource{
+		Create:   resourceSoftLayerSubnetCreate,
+		Read:     resourceSoftLayerSubnetRead,
+		Delete:   resourceSoftLayerSubnetDelete,
+		Exists:   resourceSoftLayerSubnetExists,
+		Importer: &schema.ResourceImporter{},
+
+		Schema: map[string]*schema.Schema{
+			"vlan_id": {
+				Type:     schema.TypeInt,
+				Required: true,
+				ForceNew: true,
+			},
+			"subnet_size": {
+				Type:     schema.TypeInt,
+				Required: true,
+				ForceNew: true,
+			},
+			"type": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+				ValidateFunc: func(v interface{}, k string) (ws []string, errs []error) {
+					subnetType := v.(string)
+					if subnetType != "STATIC" && subnetType != "PORTABLE" {
+						errs = append(errs, errors.New(
+							"subnet type should be either 'STATIC' or 'PORTABLE'"))
+					}
+					return
+				},
+			},
+			"endpoint_ip": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"cidr": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"network_identifier": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"gateway": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"ip_addresses": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func resourceSoftLayerSubnetCreate(d *schema.ResourceData, meta interface{}) error {
+	sess := meta.(ProviderConfig).SoftLayerSession()
+
+	productOrderContainer, err := buildSubnetProductOrderContainer(d, sess)
+	if err != nil {
+		return fmt.Errorf("Error creating subnet: %s", err)
+	}
+
+	receipt, err := services.GetProductOrderService(sess).
+		PlaceOrder(productOrderContainer, sl.Bool(false))
+	if err != nil {
+		return fmt.Errorf("Error during creation of subnet: %s", err)
+	}
+
+	subnet, err := findSubnetByOrderId(sess, *receipt.OrderId)
+	if err != nil {
+		return fmt.Errorf("Error creating subnet: %s", err)
+	}
+
+	d.SetId(fmt.Sprintf("%d", *subnet.Id))
+	return resourceSoftLayerSubnetRead(d, meta)
+}
+
+func resourceSoftLayerSubnetRead(d *schema.ResourceData, meta interface{}) error {
+	sess := meta.(ProviderConfig).SoftLayerSession()
+	service := services.GetNetworkSubnetService(sess)
+
+	subnetId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return fmt.Errorf("Not a valid subnet ID, must be an integer: %s", err)
+	}
+
+	subnet, err := service.Id(subnetId).Mask(SubnetMask).GetObject()
+	if err != nil {
+		return fmt.Errorf("Error retrieving subnet: %s", err)
+	}
+
+	d.Set("cidr", *subnet.Cidr)
+	d.Set("network_identifier", *subnet.NetworkIdentifier)
+	d.Set("gateway", sl.Get(subnet.Gateway, ""))
+
+	if subnet.NetworkVlan != nil {
+		d.Set("vlan_id", *subnet.NetworkVlan.Id)
+	}
+
+	ipAddresses, err := service.Id(subnetId).GetIpAddresses()
+	if err != nil {
+		return fmt.Errorf("Error retrieving subnet IP addresses: %s", err)
+	}
+
+	ips := make([]string, 0, len(ipAddresses))
+	for _, ip := range ipAddresses {
+		ips = append(ips, *ip.IpAddress)
+	}
+	d.Set("ip_addresses", ips)
+
+	return nil
+}
+
+func resourceSoftLayerSubnetDelete(d *schema.ResourceData, meta interface{}) error {
+	sess := meta.(ProviderConfig).SoftLayerSession()
+	service := services.GetNetworkSubnetService(sess)
+
+	subnetId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return fmt.Errorf("Not a valid subnet ID, must be an integer: %s", err)
+	}
+
+	billingItem, err := service.Id(subnetId).GetBillingItem()
+	if err != nil {
+		return fmt.Errorf("Error deleting subnet: %s", err)
+	}
+
+	if billingItem.Id == nil {
+		return nil
+	}
+
+	_, err = services.GetBillingItemService(sess).Id(*billingItem.Id).CancelService()
+	if err != nil {
+		return fmt.Errorf("Error deleting subnet: %s", err)
+	}
+
+	return nil
+}
+
+func resourceSoftLayerSubnetExists(d *schema.ResourceData, meta interface{}) (bool, error) {
+	sess := meta.(ProviderConfig).SoftLayerSession()
+	service := services.GetNetworkSubnetService(sess)
+
+	subnetId, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return false, fmt.Errorf("Not a valid subnet ID, must be an integer: %s", err)
+	}
+
+	result, err := service.Id(subnetId).Mask("id").GetObject()
+	if err != nil {
+		if apiErr, ok := err.(sl.Error); ok {
+			if apiErr.StatusCode == 404 {
+				return false, nil
+			}
+		}
+
+		return false, fmt.Errorf("Error obtaining subnet: %s", err)
+	}
+
+	return result.Id != nil && *result.Id == subnetId, nil
+}
+
+// findSubnetByOrderId mirrors findVlanByOrderId: it waits for the new subnet to show
+// up on the account, keyed off the billing order that created it.
+func findSubnetByOrderId(sess *session.Session, orderId int) (datatypes.Network_Subnet, error) {
+	stateConf := &resource.StateChangeConf{
+		Pending: []string{"pending"},
+		Target:  []string{"complete"},
+		Refresh: func() (interface{}, string, error) {
+			subnets, err := services.GetAccountService(sess).
+				Filter(filter.Path("subnets.billingItem.orderItem.order.id").
+					Eq(strconv.Itoa(orderId)).Build()).
+				Mask("id").
+				GetSubnets()
+			if err != nil {
+				return datatypes.Network_Subnet{}, "", err
+			}
+
+			if len(subnets) == 1 {
+				return subnets[0], "complete", nil
+			} else if len(subnets) == 0 {
+				return nil, "pending", nil
+			} else {
+				return nil, "", fmt.Errorf("Expected one subnet, found %d", len(subnets))
+			}
+		},
+		Timeout:    10 * time.Minute,
+		Delay:      5 * time.Second,
+		MinTimeout: 3 * time.Second,
+	}
+
+	pendingResult, err := stateConf.WaitForState()
+	if err != nil {
+		return datatypes.Network_Subnet{}, err
+	}
+
+	var result, ok = pendingResult.(datatypes.Network_Subnet)
+
+	if ok {
+		return result, nil
+	}
+
+	return datatypes.Network_Subnet{},
+		fmt.Errorf("Cannot find subnet with order id '%d'", orderId)
+}
+
+func buildSubnetProductOrderContainer(d *schema.ResourceData, sess *session.Session) (
+	*datatypes.Container_Product_Order_Network_Subnet, error) {
+	vlanId := d.Get("vlan_id").(int)
+	subnetType := d.Get("type").(string)
+	endpointIp := d.Get("endpoint_ip").(string)
+
+	vlan, err := services.GetNetworkVlanService(sess).Id(vlanId).Mask("id").GetObject()
+	if err != nil {
+		return &datatypes.Container_Product_Order_Network_Subnet{}, err
+	}
+
+	pkg, err := product.GetPackageByType(sess, AdditionalServicesNetworkVlanPackageType)
+	if err != nil {
+		return &datatypes.Container_Product_Order_Network_Subnet{}, err
+	}
+
+	productItems, err := product.GetPackageProducts(sess, *pkg.Id)
+	if err != nil {
+		return &datatypes.Container_Product_Order_Network_Subnet{}, err
+	}
+
+	subnetKeyname := strconv.Itoa(d.Get("subnet_size").(int)) + "_" + subnetType + "_PUBLIC_IP_ADDRESSES"
+
+	subnetItems := []datatypes.Product_Item{}
+	for _, item := range productItems {
+		if strings.Contains(*item.KeyName, subnetKeyname) {
+			subnetItems = append(subnetItems, item)
+		}
+	}
+
+	if len(subnetItems) == 0 {
+		return &datatypes.Container_Product_Order_Network_Subnet{},
+			fmt.Errorf("No product items matching %s could be found", subnetKeyname)
+	}
+
+	productOrderContainer := datatypes.Container_Product_Order_Network_Subnet{
+		Container_Product_Order: datatypes.Container_Product_Order{
+			PackageId: pkg.Id,
+			Prices: []datatypes.Product_Item_Price{
+				{
+					Id: subnetItems[0].Prices[0].Id,
+				},
+			},
+			Quantity: sl.Int(1),
+		},
+		VlanId: vlan.Id,
+	}
+
+	if len(endpointIp) > 0 {
+		productOrderContainer.EndPointIpAddress = sl.String(endpointIp)
+	}
+
+	return &productOrderContainer, nil
+}
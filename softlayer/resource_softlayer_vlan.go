@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"math/rand"
 	"regexp"
 	"strconv"
 	"strings"
@@ -25,8 +27,24 @@ const (
 	AdditionalServicesPackageType            = "ADDITIONAL_SERVICES"
 	AdditionalServicesNetworkVlanPackageType = "ADDITIONAL_SERVICES_NETWORK_VLAN"
 
+	DedicatedFirewallKeyName   = "HARDWARE_FIREWALL_DEDICATED"
+	DedicatedFirewallHAKeyName = "HARDWARE_FIREWALL_HIGH_AVAILABILITY"
+
 	VlanMask = "id,name,primaryRouter[datacenter[name]],primaryRouter[hostname],vlanNumber," +
-		"billingItem[recurringFee],guestNetworkComponentCount,subnets[networkIdentifier,cidr,subnetType]"
+		"billingItem[recurringFee],guestNetworkComponentCount,subnets[networkIdentifier,cidr,subnetType,version]"
+
+	// maxSubnetAddressCount caps the reported size of IPv6 subnets whose true address count
+	// would overflow a signed 64-bit int (any prefix of /65 or shorter), which includes the
+	// subnets SoftLayer actually issues (/64, /56, /48, ...).
+	maxSubnetAddressCount = 1 << 62
+
+	VlanFirewallMask = "networkVlanFirewall[id,billingItem[id]," +
+		"firewallContextAccessControlLists[id,rules]]"
+
+	defaultRetryMaxAttempts     = 5
+	defaultRetryInitialInterval = 1 * time.Second
+	defaultRetryMaxInterval     = 60 * time.Second
+	defaultRetryMultiplier      = 2.0
 )
 
 func resourceSoftLayerVlan() *schema.Resource {
@@ -38,6 +56,12 @@ func resourceSoftLayerVlan() *schema.Resource {
 		Exists:   resourceSoftLayerVlanExists,
 		Importer: &schema.ResourceImporter{},
 
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+			Read:   schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(30 * time.Minute),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"rid": {
 				Type:     schema.TypeInt,
@@ -66,6 +90,24 @@ func resourceSoftLayerVlan() *schema.Resource {
 				Required: true,
 				ForceNew: true,
 			},
+			"ip_version": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "v4",
+				ForceNew: true,
+				ValidateFunc: func(v interface{}, k string) (ws []string, errs []error) {
+					ipVersion := v.(string)
+					if ipVersion != "v4" && ipVersion != "v6" {
+						errs = append(errs, errors.New("ip_version should be either 'v4' or 'v6'"))
+					}
+					return
+				},
+			},
+			"ipv6_static_subnet_size": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				ForceNew: true,
+			},
 			"primary_subnet_size": {
 				Type:     schema.TypeInt,
 				Optional: true,
@@ -115,6 +157,99 @@ func resourceSoftLayerVlan() *schema.Resource {
 							Type:     schema.TypeInt,
 							Optional: true,
 						},
+						"ip_version": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+					},
+				},
+			},
+			"dedicated_firewall": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"enabled": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  true,
+						},
+						"ha": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
+							ForceNew: true,
+						},
+						"rules": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"action": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"protocol": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"src_ip_address": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"src_ip_cidr": {
+										Type:     schema.TypeInt,
+										Required: true,
+									},
+									"dst_ip_address": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"dst_ip_cidr": {
+										Type:     schema.TypeInt,
+										Required: true,
+									},
+									"dst_port_range_start": {
+										Type:     schema.TypeInt,
+										Required: true,
+									},
+									"dst_port_range_end": {
+										Type:     schema.TypeInt,
+										Required: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"retry": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"max_attempts": {
+							Type:     schema.TypeInt,
+							Optional: true,
+							Default:  defaultRetryMaxAttempts,
+						},
+						"initial_interval": {
+							Type:     schema.TypeInt,
+							Optional: true,
+							Default:  int(defaultRetryInitialInterval / time.Second),
+						},
+						"max_interval": {
+							Type:     schema.TypeInt,
+							Optional: true,
+							Default:  int(defaultRetryMaxInterval / time.Second),
+						},
+						"multiplier": {
+							Type:     schema.TypeFloat,
+							Optional: true,
+							Default:  defaultRetryMultiplier,
+						},
 					},
 				},
 			},
@@ -122,6 +257,87 @@ func resourceSoftLayerVlan() *schema.Resource {
 	}
 }
 
+// retryConfig describes the jittered exponential backoff applied to softlayer-go calls made on
+// behalf of this resource: sleep = min(max_interval, initial_interval * multiplier^attempt) ± jitter.
+type retryConfig struct {
+	maxAttempts     int
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	multiplier      float64
+}
+
+func resourceRetryConfig(d *schema.ResourceData) *retryConfig {
+	rc := &retryConfig{
+		maxAttempts:     defaultRetryMaxAttempts,
+		initialInterval: defaultRetryInitialInterval,
+		maxInterval:     defaultRetryMaxInterval,
+		multiplier:      defaultRetryMultiplier,
+	}
+
+	if v, ok := d.GetOk("retry"); ok {
+		conf := v.([]interface{})[0].(map[string]interface{})
+		if maxAttempts := conf["max_attempts"].(int); maxAttempts > 0 {
+			rc.maxAttempts = maxAttempts
+		}
+		if initialInterval := conf["initial_interval"].(int); initialInterval > 0 {
+			rc.initialInterval = time.Duration(initialInterval) * time.Second
+		}
+		if maxInterval := conf["max_interval"].(int); maxInterval > 0 {
+			rc.maxInterval = time.Duration(maxInterval) * time.Second
+		}
+		if multiplier := conf["multiplier"].(float64); multiplier > 0 {
+			rc.multiplier = multiplier
+		}
+	}
+
+	return rc
+}
+
+// backoff returns a jittered exponential backoff duration for the given 0-indexed attempt.
+func (rc *retryConfig) backoff(attempt int) time.Duration {
+	interval := float64(rc.initialInterval) * math.Pow(rc.multiplier, float64(attempt))
+	if interval > float64(rc.maxInterval) {
+		interval = float64(rc.maxInterval)
+	}
+
+	jitter := interval * 0.2 * (rand.Float64()*2 - 1)
+	interval += jitter
+	if interval < 0 {
+		interval = 0
+	}
+
+	return time.Duration(interval)
+}
+
+// do calls fn, retrying with jittered exponential backoff while shouldRetry allows it, up to
+// maxAttempts tries.
+func (rc *retryConfig) do(shouldRetry func(error) bool, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < rc.maxAttempts; attempt++ {
+		err = fn()
+		if err == nil || !shouldRetry(err) {
+			return err
+		}
+
+		time.Sleep(rc.backoff(attempt))
+	}
+
+	return err
+}
+
+// alwaysRetry treats every error from a softlayer-go call as transient and worth retrying.
+func alwaysRetry(err error) bool {
+	return true
+}
+
+// retryBefore returns a shouldRetry predicate for retryConfig.do that keeps retrying any error
+// until deadline passes.
+func retryBefore(deadline time.Time) func(error) bool {
+	return func(err error) bool {
+		return time.Now().Before(deadline)
+	}
+}
+
 func resourceSoftLayerVlanCreate(d *schema.ResourceData, meta interface{}) error {
 	sess := meta.(ProviderConfig).SoftLayerSession()
 	router := d.Get("router_hostname").(string)
@@ -145,13 +361,23 @@ func resourceSoftLayerVlanCreate(d *schema.ResourceData, meta interface{}) error
 
 	log.Println("[INFO] Creating vlan")
 
-	receipt, err := services.GetProductOrderService(sess).
-		PlaceOrder(productOrderContainer, sl.Bool(false))
+	rc := resourceRetryConfig(d)
+
+	var receipt datatypes.Container_Product_Order_Receipt
+	err = rc.do(alwaysRetry, func() error {
+		var placeErr error
+		receipt, placeErr = services.GetProductOrderService(sess).
+			PlaceOrder(productOrderContainer, sl.Bool(false))
+		return placeErr
+	})
 	if err != nil {
 		return fmt.Errorf("Error during creation of vlan: %s", err)
 	}
 
-	vlan, err := findVlanByOrderId(sess, *receipt.OrderId)
+	vlan, err := findVlanByOrderId(sess, *receipt.OrderId, d.Timeout(schema.TimeoutCreate), rc)
+	if err != nil {
+		return fmt.Errorf("Error creating vlan: %s", err)
+	}
 
 	if len(name) > 0 {
 		_, err = services.GetNetworkVlanService(sess).
@@ -161,10 +387,51 @@ func resourceSoftLayerVlanCreate(d *schema.ResourceData, meta interface{}) error
 		}
 	}
 
+	if firewall, ok := d.GetOk("dedicated_firewall"); ok {
+		firewallConf := firewall.([]interface{})[0].(map[string]interface{})
+		if firewallConf["enabled"].(bool) {
+			aclId, err := waitForFirewallContextAccessControlList(sess, *vlan.Id)
+			if err != nil {
+				return fmt.Errorf("Error waiting for dedicated firewall on vlan: %s", err)
+			}
+
+			if rules, ok := firewallConf["rules"].([]interface{}); ok && len(rules) > 0 {
+				if err := updateFirewallRules(sess, aclId, rules); err != nil {
+					return fmt.Errorf("Error applying dedicated firewall rules: %s", err)
+				}
+			}
+		}
+	}
+
 	d.SetId(fmt.Sprintf("%d", *vlan.Id))
 	return resourceSoftLayerVlanRead(d, meta)
 }
 
+// subnetAddressCount returns the number of addresses in a subnet of the given CIDR prefix,
+// capped at maxSubnetAddressCount for large IPv6 subnets.
+func subnetAddressCount(cidr int, ipVersion string) int {
+	bits := 32
+	if ipVersion == "v6" {
+		bits = 128
+	}
+
+	shift := uint(bits - cidr)
+	if shift >= 63 {
+		return maxSubnetAddressCount
+	}
+	return 1 << shift
+}
+
+// firstPrimarySubnet returns the first primary subnet matching ipVersion, or nil if none matches.
+func firstPrimarySubnet(primarySubnets []map[string]interface{}, ipVersion string) map[string]interface{} {
+	for _, subnet := range primarySubnets {
+		if subnet["ip_version"] == ipVersion {
+			return subnet
+		}
+	}
+	return nil
+}
+
 func resourceSoftLayerVlanRead(d *schema.ResourceData, meta interface{}) error {
 	sess := meta.(ProviderConfig).SoftLayerSession()
 	service := services.GetNetworkVlanService(sess)
@@ -174,8 +441,15 @@ func resourceSoftLayerVlanRead(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Not a valid vlan ID, must be an integer: %s", err)
 	}
 
-	vlan, err := service.Id(vlanId).Mask(VlanMask).GetObject()
+	rc := resourceRetryConfig(d)
+	shouldRetry := retryBefore(time.Now().Add(d.Timeout(schema.TimeoutRead)))
 
+	var vlan datatypes.Network_Vlan
+	err = rc.do(shouldRetry, func() error {
+		var getErr error
+		vlan, getErr = service.Id(vlanId).Mask(VlanMask).GetObject()
+		return getErr
+	})
 	if err != nil {
 		return fmt.Errorf("Error retrieving vlan: %s", err)
 	}
@@ -206,29 +480,84 @@ func resourceSoftLayerVlanRead(d *schema.ResourceData, meta interface{}) error {
 
 	for _, elem := range vlan.Subnets {
 		subnet := make(map[string]interface{})
+		ipVersion := "v4"
+		if elem.Version != nil && *elem.Version == 6 {
+			ipVersion = "v6"
+		}
+		size := subnetAddressCount(*elem.Cidr, ipVersion)
+
 		if validPrimaryType.MatchString(*elem.SubnetType) {
 			primarySubnet := map[string]interface{}{
 				"subnet":      fmt.Sprintf("%s/%d", *elem.NetworkIdentifier, *elem.Cidr),
 				"subnet_type": *elem.SubnetType,
-				"subnet_size": 1 << uint(32-*elem.Cidr),
+				"subnet_size": size,
+				"ip_version":  ipVersion,
 			}
 			primarySubnets = append(primarySubnets, primarySubnet)
 		}
 		subnet["subnet"] = fmt.Sprintf("%s/%s", *elem.NetworkIdentifier, strconv.Itoa(*elem.Cidr))
 		subnet["subnet_type"] = *elem.SubnetType
-		subnet["subnet_size"] = 1 << (uint)(32-*elem.Cidr)
+		subnet["subnet_size"] = size
+		subnet["ip_version"] = ipVersion
 		subnets = append(subnets, subnet)
 	}
 	d.Set("subnets", subnets)
 
-	if primarySubnets != nil && len(primarySubnets) > 0 {
+	// A VLAN can have both a v4 and a v6 primary subnet; subnet_size/ip_version keep describing
+	// the v4 one for backwards compatibility, picking the first primary subnet if there's no v4.
+	if primarySubnet := firstPrimarySubnet(primarySubnets, "v4"); primarySubnet != nil {
+		d.Set("subnet_size", primarySubnet["subnet_size"])
+		d.Set("ip_version", primarySubnet["ip_version"])
+	} else if len(primarySubnets) > 0 {
 		d.Set("subnet_size", primarySubnets[0]["subnet_size"])
+		d.Set("ip_version", primarySubnets[0]["ip_version"])
 	} else if vlan.Subnets != nil && len(vlan.Subnets) > 0 {
-		d.Set("subnet_size", 1<<(uint)(32-*vlan.Subnets[0].Cidr))
+		fallbackIpVersion := "v4"
+		if vlan.Subnets[0].Version != nil && *vlan.Subnets[0].Version == 6 {
+			fallbackIpVersion = "v6"
+		}
+		d.Set("subnet_size", subnetAddressCount(*vlan.Subnets[0].Cidr, fallbackIpVersion))
+		d.Set("ip_version", fallbackIpVersion)
 	} else {
 		d.Set("subnet_size", 0)
 	}
 
+	var firewallVlan datatypes.Network_Vlan
+	err = rc.do(shouldRetry, func() error {
+		var getErr error
+		firewallVlan, getErr = service.Id(vlanId).Mask(VlanFirewallMask).GetObject()
+		return getErr
+	})
+	if err != nil {
+		return fmt.Errorf("Error retrieving vlan: %s", err)
+	}
+
+	if firewallVlan.NetworkVlanFirewall != nil {
+		rules := make([]map[string]interface{}, 0)
+		for _, acl := range firewallVlan.NetworkVlanFirewall.FirewallContextAccessControlLists {
+			for _, rule := range acl.Rules {
+				rules = append(rules, map[string]interface{}{
+					"action":               sl.Get(rule.Action, ""),
+					"protocol":             sl.Get(rule.Protocol, ""),
+					"src_ip_address":       sl.Get(rule.SourceIpAddress, ""),
+					"src_ip_cidr":          sl.Get(rule.SourceIpCidr, 0),
+					"dst_ip_address":       sl.Get(rule.DestinationIpAddress, ""),
+					"dst_ip_cidr":          sl.Get(rule.DestinationIpCidr, 0),
+					"dst_port_range_start": sl.Get(rule.DestinationPortRangeStart, 0),
+					"dst_port_range_end":   sl.Get(rule.DestinationPortRangeEnd, 0),
+				})
+			}
+		}
+
+		d.Set("dedicated_firewall", []map[string]interface{}{
+			{
+				"enabled": true,
+				"ha":      strings.Contains(sl.Get(firewallVlan.NetworkVlanFirewall.FirewallType, "").(string), "HA"),
+				"rules":   rules,
+			},
+		})
+	}
+
 	return nil
 }
 
@@ -252,9 +581,118 @@ func resourceSoftLayerVlanUpdate(d *schema.ResourceData, meta interface{}) error
 	if err != nil {
 		return fmt.Errorf("Error updating vlan: %s", err)
 	}
+
+	if d.HasChange("dedicated_firewall") {
+		if err := updateDedicatedFirewall(d, sess, vlanId); err != nil {
+			return fmt.Errorf("Error updating dedicated firewall: %s", err)
+		}
+	}
+
 	return resourceSoftLayerVlanRead(d, meta)
 }
 
+// updateDedicatedFirewall reconciles dedicated_firewall in place: it orders the firewall when
+// it's newly enabled, cancels its billing item when it's disabled, and re-pushes rules whenever
+// they change while the firewall stays enabled.
+func updateDedicatedFirewall(d *schema.ResourceData, sess *session.Session, vlanId int) error {
+	oldRaw, newRaw := d.GetChange("dedicated_firewall")
+	oldConf := firstFirewallConf(oldRaw.([]interface{}))
+	newConf := firstFirewallConf(newRaw.([]interface{}))
+
+	wasEnabled := oldConf != nil && oldConf["enabled"].(bool)
+	isEnabled := newConf != nil && newConf["enabled"].(bool)
+
+	if !wasEnabled && isEnabled {
+		if err := orderDedicatedFirewall(d, sess, vlanId, newConf["ha"].(bool)); err != nil {
+			return err
+		}
+	} else if wasEnabled && !isEnabled {
+		return cancelDedicatedFirewall(sess, vlanId)
+	} else if !isEnabled {
+		return nil
+	}
+
+	aclId, err := waitForFirewallContextAccessControlList(sess, vlanId)
+	if err != nil {
+		return err
+	}
+
+	rules, _ := newConf["rules"].([]interface{})
+	return updateFirewallRules(sess, aclId, rules)
+}
+
+func firstFirewallConf(list []interface{}) map[string]interface{} {
+	if len(list) == 0 {
+		return nil
+	}
+	return list[0].(map[string]interface{})
+}
+
+// orderDedicatedFirewall places the product order that attaches a dedicated hardware firewall
+// to an already-provisioned vlan.
+func orderDedicatedFirewall(d *schema.ResourceData, sess *session.Session, vlanId int, ha bool) error {
+	pkg, err := product.GetPackageByType(sess, AdditionalServicesNetworkVlanPackageType)
+	if err != nil {
+		return err
+	}
+
+	productItems, err := product.GetPackageProducts(sess, *pkg.Id)
+	if err != nil {
+		return err
+	}
+
+	firewallKeyname := DedicatedFirewallKeyName
+	if ha {
+		firewallKeyname = DedicatedFirewallHAKeyName
+	}
+
+	firewallItems := []datatypes.Product_Item{}
+	for _, item := range productItems {
+		if *item.KeyName == firewallKeyname {
+			firewallItems = append(firewallItems, item)
+		}
+	}
+
+	if len(firewallItems) == 0 {
+		return fmt.Errorf("No product items matching %s could be found", firewallKeyname)
+	}
+
+	productOrderContainer := &datatypes.Container_Product_Order_Network_Protection_Firewall_Dedicated{
+		Container_Product_Order: datatypes.Container_Product_Order{
+			PackageId: pkg.Id,
+			Prices: []datatypes.Product_Item_Price{
+				{Id: firewallItems[0].Prices[0].Id},
+			},
+			Quantity: sl.Int(1),
+		},
+		VlanId: sl.Int(vlanId),
+	}
+
+	rc := resourceRetryConfig(d)
+	return rc.do(alwaysRetry, func() error {
+		_, placeErr := services.GetProductOrderService(sess).PlaceOrder(productOrderContainer, sl.Bool(false))
+		return placeErr
+	})
+}
+
+// cancelDedicatedFirewall cancels the billing item for a vlan's dedicated hardware firewall,
+// leaving the vlan itself untouched.
+func cancelDedicatedFirewall(sess *session.Session, vlanId int) error {
+	firewallVlan, err := services.GetNetworkVlanService(sess).Id(vlanId).Mask(VlanFirewallMask).GetObject()
+	if err != nil {
+		return err
+	}
+
+	if firewallVlan.NetworkVlanFirewall == nil || firewallVlan.NetworkVlanFirewall.BillingItem == nil ||
+		firewallVlan.NetworkVlanFirewall.BillingItem.Id == nil {
+		return nil
+	}
+
+	_, err = services.GetBillingItemService(sess).
+		Id(*firewallVlan.NetworkVlanFirewall.BillingItem.Id).CancelService()
+	return err
+}
+
 func resourceSoftLayerVlanDelete(d *schema.ResourceData, meta interface{}) error {
 	sess := meta.(ProviderConfig).SoftLayerSession()
 	service := services.GetNetworkVlanService(sess)
@@ -264,7 +702,36 @@ func resourceSoftLayerVlanDelete(d *schema.ResourceData, meta interface{}) error
 		return fmt.Errorf("Not a valid vlan ID, must be an integer: %s", err)
 	}
 
-	billingItem, err := service.Id(vlanId).GetBillingItem()
+	rc := resourceRetryConfig(d)
+
+	var firewallVlan datatypes.Network_Vlan
+	err = rc.do(alwaysRetry, func() error {
+		var getErr error
+		firewallVlan, getErr = service.Id(vlanId).Mask(VlanFirewallMask).GetObject()
+		return getErr
+	})
+	if err != nil {
+		return fmt.Errorf("Error deleting vlan: %s", err)
+	}
+
+	if firewallVlan.NetworkVlanFirewall != nil && firewallVlan.NetworkVlanFirewall.BillingItem != nil &&
+		firewallVlan.NetworkVlanFirewall.BillingItem.Id != nil {
+		err = rc.do(alwaysRetry, func() error {
+			_, cancelErr := services.GetBillingItemService(sess).
+				Id(*firewallVlan.NetworkVlanFirewall.BillingItem.Id).CancelService()
+			return cancelErr
+		})
+		if err != nil {
+			return fmt.Errorf("Error cancelling dedicated firewall: %s", err)
+		}
+	}
+
+	var billingItem datatypes.Billing_Item
+	err = rc.do(alwaysRetry, func() error {
+		var getErr error
+		billingItem, getErr = service.Id(vlanId).GetBillingItem()
+		return getErr
+	})
 	if err != nil {
 		return fmt.Errorf("Error deleting vlan: %s", err)
 	}
@@ -279,22 +746,22 @@ func resourceSoftLayerVlanDelete(d *schema.ResourceData, meta interface{}) error
 
 	// If the VLAN has a billing item, the function deletes the billing item and returns so that
 	// the VLAN resource in a terraform state file can be deleted. Physical VLAN will be deleted
-	// automatically which the VLAN doesn't have any child resources.
-	tries := 0
-	for {
-		_, err = services.GetBillingItemService(sess).Id(*billingItem.Id).CancelService()
-		// servers still on the VLAN
-		if err != nil {
-			if strings.Contains(err.Error(), "servers still on the VLAN") && tries < 5 {
-				log.Printf("[DEBUG] VLAN %d still has servers. Waiting to delete...\n", vlanId)
-				time.Sleep(1 * time.Minute)
-				tries = tries + 1
-				continue
-			}
-		}
-		break
+	// automatically which the VLAN doesn't have any child resources. Cancellation is retried with
+	// backoff while servers are still draining off the VLAN, for at most retry.max_attempts tries
+	// and not past the delete timeout.
+	deadline := time.Now().Add(d.Timeout(schema.TimeoutDelete))
+	retryWhileServersPresent := func(err error) bool {
+		return strings.Contains(err.Error(), "servers still on the VLAN") && time.Now().Before(deadline)
 	}
 
+	err = rc.do(retryWhileServersPresent, func() error {
+		_, cancelErr := services.GetBillingItemService(sess).Id(*billingItem.Id).CancelService()
+		if cancelErr != nil && strings.Contains(cancelErr.Error(), "servers still on the VLAN") {
+			log.Printf("[DEBUG] VLAN %d still has servers. Waiting to delete...\n", vlanId)
+		}
+		return cancelErr
+	})
+
 	return err
 }
 
@@ -321,47 +788,40 @@ func resourceSoftLayerVlanExists(d *schema.ResourceData, meta interface{}) (bool
 	return result.Id != nil && *result.Id == vlanId, nil
 }
 
-func findVlanByOrderId(sess *session.Session, orderId int) (datatypes.Network_Vlan, error) {
-	stateConf := &resource.StateChangeConf{
-		Pending: []string{"pending"},
-		Target:  []string{"complete"},
-		Refresh: func() (interface{}, string, error) {
-			vlans, err := services.GetAccountService(sess).
+// findVlanByOrderId polls for the vlan created by orderId, waiting rc's jittered exponential
+// backoff between polls, until it shows up or timeout elapses.
+func findVlanByOrderId(sess *session.Session, orderId int, timeout time.Duration, rc *retryConfig) (datatypes.Network_Vlan, error) {
+	deadline := time.Now().Add(timeout)
+	shouldRetry := retryBefore(deadline)
+
+	for attempt := 0; ; attempt++ {
+		var vlans []datatypes.Network_Vlan
+		err := rc.do(shouldRetry, func() error {
+			var getErr error
+			vlans, getErr = services.GetAccountService(sess).
 				Filter(filter.Path("networkVlans.billingItem.orderItem.order.id").
 					Eq(strconv.Itoa(orderId)).Build()).
 				Mask("id").
 				GetNetworkVlans()
-			if err != nil {
-				return datatypes.Network_Vlan{}, "", err
-			}
-
-			if len(vlans) == 1 {
-				return vlans[0], "complete", nil
-			} else if len(vlans) == 0 {
-				return nil, "pending", nil
-			} else {
-				return nil, "", fmt.Errorf("Expected one vlan: %s", err)
-			}
-		},
-		Timeout:    10 * time.Minute,
-		Delay:      5 * time.Second,
-		MinTimeout: 3 * time.Second,
-	}
-
-	pendingResult, err := stateConf.WaitForState()
+			return getErr
+		})
+		if err != nil {
+			return datatypes.Network_Vlan{}, err
+		}
 
-	if err != nil {
-		return datatypes.Network_Vlan{}, err
-	}
+		if len(vlans) == 1 {
+			return vlans[0], nil
+		} else if len(vlans) > 1 {
+			return datatypes.Network_Vlan{}, fmt.Errorf("Expected one vlan, found %d", len(vlans))
+		}
 
-	var result, ok = pendingResult.(datatypes.Network_Vlan)
+		if time.Now().After(deadline) {
+			return datatypes.Network_Vlan{},
+				fmt.Errorf("Cannot find vlan with order id '%d'", orderId)
+		}
 
-	if ok {
-		return result, nil
+		time.Sleep(rc.backoff(attempt))
 	}
-
-	return datatypes.Network_Vlan{},
-		fmt.Errorf("Cannot find vlan with order id '%d'", orderId)
 }
 
 func buildVlanProductOrderContainer(d *schema.ResourceData, sess *session.Session, packageType string) (
@@ -396,7 +856,11 @@ func buildVlanProductOrderContainer(d *schema.ResourceData, sess *session.Sessio
 
 	// 3. Find vlan and subnet prices
 	vlanKeyname := vlanType + "_NETWORK_VLAN"
+
 	subnetKeyname := strconv.Itoa(d.Get("subnet_size").(int)) + "_STATIC_PUBLIC_IP_ADDRESSES"
+	if d.Get("ip_version").(string) == "v6" {
+		subnetKeyname = strconv.Itoa(d.Get("ipv6_static_subnet_size").(int)) + "_STATIC_PUBLIC_IPV6_ADDRESSES"
+	}
 
 	// 4. Select items with a matching keyname
 	vlanItems := []datatypes.Product_Item{}
@@ -445,5 +909,98 @@ func buildVlanProductOrderContainer(d *schema.ResourceData, sess *session.Sessio
 		}
 	}
 
+	if firewall, ok := d.GetOk("dedicated_firewall"); ok {
+		firewallConf := firewall.([]interface{})[0].(map[string]interface{})
+		if firewallConf["enabled"].(bool) {
+			firewallKeyname := DedicatedFirewallKeyName
+			if firewallConf["ha"].(bool) {
+				firewallKeyname = DedicatedFirewallHAKeyName
+			}
+
+			firewallItems := []datatypes.Product_Item{}
+			for _, item := range productItems {
+				if *item.KeyName == firewallKeyname {
+					firewallItems = append(firewallItems, item)
+				}
+			}
+
+			if len(firewallItems) == 0 {
+				return &datatypes.Container_Product_Order_Network_Vlan{},
+					fmt.Errorf("No product items matching %s could be found", firewallKeyname)
+			}
+
+			productOrderContainer.Prices = append(productOrderContainer.Prices,
+				datatypes.Product_Item_Price{Id: firewallItems[0].Prices[0].Id})
+		}
+	}
+
 	return &productOrderContainer, nil
 }
+
+// waitForFirewallContextAccessControlList polls the vlan's dedicated firewall until SoftLayer
+// has provisioned a firewall context access control list for it, then returns that list's id so
+// rules can be pushed to it.
+func waitForFirewallContextAccessControlList(sess *session.Session, vlanId int) (int, error) {
+	stateConf := &resource.StateChangeConf{
+		Pending: []string{"pending"},
+		Target:  []string{"complete"},
+		Refresh: func() (interface{}, string, error) {
+			vlan, err := services.GetNetworkVlanService(sess).
+				Id(vlanId).
+				Mask(VlanFirewallMask).
+				GetObject()
+			if err != nil {
+				return nil, "", err
+			}
+
+			if vlan.NetworkVlanFirewall == nil || len(vlan.NetworkVlanFirewall.FirewallContextAccessControlLists) == 0 {
+				return nil, "pending", nil
+			}
+
+			return vlan.NetworkVlanFirewall.FirewallContextAccessControlLists[0], "complete", nil
+		},
+		Timeout:    20 * time.Minute,
+		Delay:      10 * time.Second,
+		MinTimeout: 5 * time.Second,
+	}
+
+	pendingResult, err := stateConf.WaitForState()
+	if err != nil {
+		return 0, err
+	}
+
+	acl, ok := pendingResult.(datatypes.Network_Firewall_Context_Access_Control_List)
+	if !ok || acl.Id == nil {
+		return 0, fmt.Errorf("Cannot find firewall context access control list for vlan '%d'", vlanId)
+	}
+
+	return *acl.Id, nil
+}
+
+// updateFirewallRules pushes the given rule blocks to the firewall context identified by aclId
+// via a SoftLayer_Network_Firewall_Update_Request.
+func updateFirewallRules(sess *session.Session, aclId int, rules []interface{}) error {
+	firewallRules := make([]datatypes.Network_Firewall_Update_Request_Rule, 0, len(rules))
+	for i, elem := range rules {
+		rule := elem.(map[string]interface{})
+		firewallRules = append(firewallRules, datatypes.Network_Firewall_Update_Request_Rule{
+			OrderValue:                sl.Int(i + 1),
+			Action:                    sl.String(rule["action"].(string)),
+			Protocol:                  sl.String(rule["protocol"].(string)),
+			SourceIpAddress:           sl.String(rule["src_ip_address"].(string)),
+			SourceIpCidr:              sl.Int(rule["src_ip_cidr"].(int)),
+			DestinationIpAddress:      sl.String(rule["dst_ip_address"].(string)),
+			DestinationIpCidr:         sl.Int(rule["dst_ip_cidr"].(int)),
+			DestinationPortRangeStart: sl.Int(rule["dst_port_range_start"].(int)),
+			DestinationPortRangeEnd:   sl.Int(rule["dst_port_range_end"].(int)),
+		})
+	}
+
+	updateRequest := datatypes.Network_Firewall_Update_Request{
+		FirewallContextAccessControlListId: sl.Int(aclId),
+		Rules: firewallRules,
+	}
+
+	_, err := services.GetNetworkFirewallUpdateRequestService(sess).CreateObject(&updateRequest)
+	return err
+}
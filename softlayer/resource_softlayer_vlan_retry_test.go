@@ -0,0 +1,68 @@
+package softlayer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryConfigBackoffRespectsMaxInterval(t *testing.T) {
+	rc := &retryConfig{
+		initialInterval: 1 * time.Second,
+		maxInterval:     5 * time.Second,
+		multiplier:      2.0,
+	}
+
+	// At attempt 10 the unjittered interval would be far beyond maxInterval; backoff must clamp
+	// to maxInterval before applying jitter, so even the jittered result stays within +/-20% of it.
+	d := rc.backoff(10)
+	lower := time.Duration(float64(rc.maxInterval) * 0.8)
+	upper := time.Duration(float64(rc.maxInterval) * 1.2)
+	if d < lower || d > upper {
+		t.Errorf("backoff(10) = %s, expected to be clamped within [%s, %s]", d, lower, upper)
+	}
+}
+
+func TestRetryConfigDoStopsWhenShouldRetryReturnsFalse(t *testing.T) {
+	rc := &retryConfig{
+		maxAttempts:     5,
+		initialInterval: time.Millisecond,
+		maxInterval:     time.Millisecond,
+		multiplier:      1.0,
+	}
+
+	attempts := 0
+	err := rc.do(func(error) bool { return false }, func() error {
+		attempts++
+		return errors.New("boom")
+	})
+
+	if err == nil {
+		t.Fatalf("expected do to return the underlying error")
+	}
+	if attempts != 1 {
+		t.Errorf("do called fn %d times, expected exactly 1 when shouldRetry always returns false", attempts)
+	}
+}
+
+func TestRetryConfigDoStopsAtMaxAttempts(t *testing.T) {
+	rc := &retryConfig{
+		maxAttempts:     3,
+		initialInterval: time.Millisecond,
+		maxInterval:     time.Millisecond,
+		multiplier:      1.0,
+	}
+
+	attempts := 0
+	err := rc.do(alwaysRetry, func() error {
+		attempts++
+		return errors.New("boom")
+	})
+
+	if err == nil {
+		t.Fatalf("expected do to return the underlying error")
+	}
+	if attempts != rc.maxAttempts {
+		t.Errorf("do called fn %d times, expected maxAttempts (%d)", attempts, rc.maxAttempts)
+	}
+}
@@ -0,0 +1,41 @@
+package softlayer
+
+import "testing"
+
+func TestSubnetAddressCount(t *testing.T) {
+	cases := []struct {
+		cidr      int
+		ipVersion string
+		expected  int
+	}{
+		{cidr: 24, ipVersion: "v4", expected: 256},
+		{cidr: 29, ipVersion: "v4", expected: 8},
+		{cidr: 0, ipVersion: "v4", expected: 1 << 32},
+		{cidr: 64, ipVersion: "v6", expected: maxSubnetAddressCount},
+		{cidr: 56, ipVersion: "v6", expected: maxSubnetAddressCount},
+		{cidr: 48, ipVersion: "v6", expected: maxSubnetAddressCount},
+	}
+
+	for _, c := range cases {
+		actual := subnetAddressCount(c.cidr, c.ipVersion)
+		if actual != c.expected {
+			t.Errorf("subnetAddressCount(%d, %q) = %d, expected %d", c.cidr, c.ipVersion, actual, c.expected)
+		}
+	}
+}
+
+func TestFirstPrimarySubnet(t *testing.T) {
+	subnets := []map[string]interface{}{
+		{"ip_version": "v6", "subnet": "2001:db8::/64"},
+		{"ip_version": "v4", "subnet": "10.0.0.0/24"},
+	}
+
+	v4 := firstPrimarySubnet(subnets, "v4")
+	if v4 == nil || v4["subnet"] != "10.0.0.0/24" {
+		t.Errorf("firstPrimarySubnet(subnets, \"v4\") = %v, expected the 10.0.0.0/24 entry", v4)
+	}
+
+	if firstPrimarySubnet(subnets, "v5") != nil {
+		t.Errorf("firstPrimarySubnet(subnets, \"v5\") should be nil when no subnet matches")
+	}
+}